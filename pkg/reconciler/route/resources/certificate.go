@@ -0,0 +1,51 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"knative.dev/pkg/kmeta"
+	netv1alpha1 "knative.dev/serving/pkg/apis/networking/v1alpha1"
+	"knative.dev/serving/pkg/apis/serving"
+	servingv1 "knative.dev/serving/pkg/apis/serving/v1"
+)
+
+// MakeClusterLocalCertificate creates the Certificate that terminates TLS
+// for the cluster-local hostnames of the named target within r (see
+// ClusterLocalHostNames), so mesh/east-west traffic can be terminated at the
+// ingress with SNI. It's labeled via CertificateLabels so downstream ingress
+// implementations (Kourier/Contour/Istio) can tell it apart from the
+// external-domain Certificate for the same target and wire it to the
+// cluster-local listener.
+func MakeClusterLocalCertificate(r *servingv1.Route, targetName, serviceName, secretName string) *netv1alpha1.Certificate {
+	return &netv1alpha1.Certificate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      kmeta.ChildName(r.Name, "-"+targetName+"-cluster-local"),
+			Namespace: r.Namespace,
+			Labels: kmeta.UnionMaps(map[string]string{
+				serving.RouteLabelKey:          r.Name,
+				serving.RouteNamespaceLabelKey: r.Namespace,
+			}, CertificateLabels(netv1alpha1.IngressVisibilityClusterLocal)),
+			OwnerReferences: []metav1.OwnerReference{*kmeta.NewControllerRef(r)},
+		},
+		Spec: netv1alpha1.CertificateSpec{
+			DNSNames:   ClusterLocalHostNames(serviceName, r.Namespace),
+			SecretName: secretName,
+		},
+	}
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	netv1alpha1 "knative.dev/serving/pkg/apis/networking/v1alpha1"
+	servingv1 "knative.dev/serving/pkg/apis/serving/v1"
+)
+
+func TestCertificateLabels(t *testing.T) {
+	cases := []struct {
+		name       string
+		visibility netv1alpha1.IngressVisibility
+		want       string
+	}{
+		{"external", netv1alpha1.IngressVisibilityExternalIP, CertificateTypeExternalDomain},
+		{"cluster-local", netv1alpha1.IngressVisibilityClusterLocal, CertificateTypeClusterLocalDomain},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := CertificateLabels(c.visibility)
+			if got[CertificateTypeLabelKey] != c.want {
+				t.Errorf("CertificateLabels(%v)[%s] = %q, want %q", c.visibility, CertificateTypeLabelKey, got[CertificateTypeLabelKey], c.want)
+			}
+		})
+	}
+}
+
+func TestMakeClusterLocalCertificateIsLabeled(t *testing.T) {
+	r := &servingv1.Route{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-route", Namespace: "default"},
+	}
+
+	cert := MakeClusterLocalCertificate(r, "my-route", "my-route-00001", "my-route-cluster-local-cert")
+
+	want := CertificateTypeClusterLocalDomain
+	if got := cert.Labels[CertificateTypeLabelKey]; got != want {
+		t.Errorf("cert.Labels[%s] = %q, want %q", CertificateTypeLabelKey, got, want)
+	}
+
+	wantHosts := ClusterLocalHostNames("my-route-00001", "default")
+	if len(cert.Spec.DNSNames) != len(wantHosts) {
+		t.Fatalf("cert.Spec.DNSNames = %v, want %v", cert.Spec.DNSNames, wantHosts)
+	}
+	for i, h := range wantHosts {
+		if cert.Spec.DNSNames[i] != h {
+			t.Errorf("cert.Spec.DNSNames[%d] = %q, want %q", i, cert.Spec.DNSNames[i], h)
+		}
+	}
+}
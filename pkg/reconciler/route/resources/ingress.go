@@ -18,12 +18,18 @@ package resources
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	"knative.dev/pkg/kmeta"
 	"knative.dev/serving/pkg/activator"
@@ -40,6 +46,77 @@ import (
 	"knative.dev/serving/pkg/reconciler/route/traffic"
 )
 
+const (
+	// CertificateTypeLabelKey is applied to the Certificate resources backing
+	// an Ingress's TLS entries so that downstream ingress implementations
+	// (e.g. Kourier, Contour, Istio) can tell which listener a certificate
+	// should be wired to.
+	CertificateTypeLabelKey = "networking.knative.dev/certificate-type"
+
+	// CertificateTypeExternalDomain is the CertificateTypeLabelKey value for
+	// Certificates covering externally-visible hostnames.
+	CertificateTypeExternalDomain = "external-domain"
+
+	// CertificateTypeClusterLocalDomain is the CertificateTypeLabelKey value
+	// for Certificates covering cluster-local hostnames
+	// (svc.cluster.local, .svc, and the short in-namespace name).
+	CertificateTypeClusterLocalDomain = "cluster-local-domain"
+
+	// StickySessionAnnotationKey, when set on a Route, pins a client to a
+	// single revision for the lifetime of a session instead of having every
+	// request re-evaluate the percent-weighted split. Its value is the name
+	// of the HTTP request header used to carry the pin.
+	//
+	// This is manual header-pin affinity, not browser-cookie session
+	// affinity: netv1alpha1.HeaderMatch only supports an exact match against
+	// a single named request header, it doesn't parse a `Cookie:` header for
+	// a `name=value` pair. The caller (an edge proxy/gateway, or a
+	// non-browser client) is responsible for setting this header itself on
+	// every request it wants pinned; nothing in this package sets a
+	// `Set-Cookie` response header or otherwise auto-pins a client on its
+	// first, unpinned request. Real browser-cookie affinity -- matching an
+	// existing `Cookie:` header and auto-pinning a client via `Set-Cookie` on
+	// first response -- needs a cookie-aware HeaderMatch predicate plus a
+	// queue-proxy change to emit that response header; neither is part of
+	// this package and both are out of scope here.
+	StickySessionAnnotationKey = "serving.knative.dev/sticky-session"
+
+	// stickySessionPinHintHeaderName carries, in the AppendHeaders of each
+	// split of the percent-weighted paths (the base path and any tag-based
+	// routing paths), the "header=value" pair a client would need to
+	// send back (as the StickySessionAnnotationKey-named request header) to
+	// pin to the revision that served this response. Nothing downstream of
+	// this package currently consumes it: there's no queue-proxy logic that
+	// turns it into a `Set-Cookie` (or any other) response header, so
+	// automatic pin-on-first-response isn't implemented -- only clients that
+	// already send the pin header on every request (see
+	// StickySessionAnnotationKey) get session affinity today.
+	stickySessionPinHintHeaderName = "Knative-Serving-Sticky-Pin-Hint"
+
+	// RetryAttemptsAnnotationKey configures the number of times the ingress
+	// should retry a request before giving up, applied to every path
+	// generated for the Route except the ACME challenge paths. There's no
+	// TrafficTarget.Retry CRD field yet to let this vary per-target (see
+	// ingressPathOptions), so this is intentionally Route-wide for now.
+	RetryAttemptsAnnotationKey = "serving.knative.dev/retry-attempts"
+
+	// RetryPerTryTimeoutAnnotationKey configures the per-attempt timeout
+	// (e.g. "2s") for retries enabled via RetryAttemptsAnnotationKey.
+	RetryPerTryTimeoutAnnotationKey = "serving.knative.dev/retry-per-try-timeout"
+
+	// RetryOnAnnotationKey is a comma-separated list of conditions under
+	// which a request should be retried (e.g. "5xx,reset"), used alongside
+	// RetryAttemptsAnnotationKey.
+	RetryOnAnnotationKey = "serving.knative.dev/retry-on"
+
+	// HeaderMatchAnnotationKey carries a JSON object of header name to
+	// exact-match value (e.g. `{"X-Canary":"true"}`); only requests with a
+	// matching header are routed to the Route's targets. There's no
+	// TrafficTarget.Headers CRD field yet to express this per-target, so
+	// this is intentionally Route-wide for now.
+	HeaderMatchAnnotationKey = "serving.knative.dev/header-match"
+)
+
 // MakeIngressTLS creates IngressTLS to configure the ingress TLS.
 func MakeIngressTLS(cert *netv1alpha1.Certificate, hostNames []string) netv1alpha1.IngressTLS {
 	return netv1alpha1.IngressTLS{
@@ -49,18 +126,49 @@ func MakeIngressTLS(cert *netv1alpha1.Certificate, hostNames []string) netv1alph
 	}
 }
 
+// ClusterLocalHostNames returns the set of cluster-local hostnames
+// (the short in-namespace name, the `.svc` name, and the full
+// `svc.cluster.local` name) that a cluster-local Certificate for the given
+// service should cover, for use with MakeIngressTLS.
+func ClusterLocalHostNames(serviceName, namespace string) []string {
+	shortName := serviceName + "." + namespace
+	return []string{
+		shortName,
+		shortName + ".svc",
+		shortName + ".svc.cluster.local",
+	}
+}
+
+// CertificateLabels returns the labels that should be applied to the
+// Certificate backing an IngressTLS entry for the given visibility, so that
+// downstream ingress implementations can distinguish external-domain certs
+// from cluster-local ones and wire them to the correct listener.
+func CertificateLabels(visibility netv1alpha1.IngressVisibility) map[string]string {
+	certType := CertificateTypeExternalDomain
+	if visibility == netv1alpha1.IngressVisibilityClusterLocal {
+		certType = CertificateTypeClusterLocalDomain
+	}
+	return map[string]string{CertificateTypeLabelKey: certType}
+}
+
 // MakeIngress creates Ingress to set up routing rules. Such Ingress specifies
 // which Hosts that it applies to, as well as the routing rules.
+//
+// externalTLS carries the IngressTLS entries for externally-visible hosts,
+// and internalTLS carries the IngressTLS entries for cluster-local hosts
+// (e.g. svc.cluster.local, .svc, and the short in-namespace name), so that
+// mesh/east-west traffic can also be terminated at the ingress with SNI.
 func MakeIngress(
 	ctx context.Context,
 	r *servingv1.Route,
 	tc *traffic.Config,
-	tls []netv1alpha1.IngressTLS,
+	externalTLS []netv1alpha1.IngressTLS,
+	internalTLS []netv1alpha1.IngressTLS,
 	ingressClass string,
 	defaults apisconfig.Defaults,
-	acmeChallenges ...netv1alpha1.HTTP01Challenge,
+	challenges ChallengeSet,
 ) (*netv1alpha1.Ingress, error) {
-	spec, err := MakeIngressSpec(ctx, r, tls, tc.Targets, tc.Visibility, defaults, acmeChallenges...)
+	spec, err := MakeIngressSpec(ctx, r, externalTLS, internalTLS, tc.Targets, tc.Visibility, defaults, challenges)
 	if err != nil {
 		return nil, err
 	}
@@ -84,14 +192,21 @@ func MakeIngress(
 }
 
 // MakeIngressSpec creates a new IngressSpec
+//
+// externalTLS and internalTLS are kept separate, rather than a single flat
+// list, so that cluster-local TLS entries can be reasoned about (and
+// extended) independently of the external-domain ones -- e.g. a
+// cluster-local Certificate doesn't imply an external one exists, and vice
+// versa.
 func MakeIngressSpec(
 	ctx context.Context,
 	r *servingv1.Route,
-	tls []netv1alpha1.IngressTLS,
+	externalTLS []netv1alpha1.IngressTLS,
+	internalTLS []netv1alpha1.IngressTLS,
 	targets map[string]traffic.RevisionTargets,
 	visibility map[string]netv1alpha1.IngressVisibility,
 	defaults apisconfig.Defaults,
-	acmeChallenges ...netv1alpha1.HTTP01Challenge,
+	challenges ChallengeSet,
 ) (netv1alpha1.IngressSpec, error) {
 	// Domain should have been specified in route status
 	// before calling this func.
@@ -103,7 +218,12 @@ func MakeIngressSpec(
 	sort.Strings(names)
 	// The routes are matching rule based on domain name to traffic split targets.
 	rules := make([]netv1alpha1.IngressRule, 0, len(names))
-	challengeHosts := getChallengeHosts(acmeChallenges)
+	challengeHosts := getChallengeHosts(challenges.HTTP01Challenges)
+	opts := ingressPathOptions{
+		stickyHeaderName: r.GetAnnotations()[StickySessionAnnotationKey],
+		retryPolicy:      retryPolicyFromAnnotations(r.GetAnnotations()),
+		headers:          headerMatchFromAnnotations(r.GetAnnotations()),
+	}
 
 	networkConfig := config.FromContext(ctx).Network
 
@@ -118,10 +238,17 @@ func MakeIngressSpec(
 			if err != nil {
 				return netv1alpha1.IngressSpec{}, err
 			}
-			rule := *makeIngressRule([]string{domain}, r.Namespace, visibility, targets[name], defaults)
+			rule := *makeIngressRule([]string{domain}, r.Namespace, visibility, targets[name], defaults, opts)
+			// makeIngressRule returns exactly one path: the percent-weighted
+			// base/split path. Grab it now, before the sticky-session and
+			// tag-based-routing blocks below prepend their own paths ahead of
+			// it in rule.HTTP.Paths -- otherwise rule.HTTP.Paths[0] stops
+			// meaning "the base path" and the AppendHeaders set here would
+			// land on a sticky-pin or tag path instead.
+			basePath := &rule.HTTP.Paths[0]
 			if networkConfig.TagHeaderBasedRouting {
-				if rule.HTTP.Paths[0].AppendHeaders == nil {
-					rule.HTTP.Paths[0].AppendHeaders = make(map[string]string)
+				if basePath.AppendHeaders == nil {
+					basePath.AppendHeaders = make(map[string]string)
 				}
 
 				if name == traffic.DefaultTarget {
@@ -129,12 +256,7 @@ func MakeIngressSpec(
 					// the header "Knative-Serving-Default-Route: true" is appended here.
 					// If the header has "true" and there is a "Knative-Serving-Tag" header,
 					// then the request is having the undefined tag header, which will be observed in queue-proxy.
-					rule.HTTP.Paths[0].AppendHeaders[network.DefaultRouteHeaderName] = "true"
-					// Add ingress paths for a request with the tag header.
-					// If a request has one of the `names`(tag name) except the default path,
-					// the request will be routed via one of the ingress paths, corresponding to the tag name.
-					rule.HTTP.Paths = append(
-						makeTagBasedRoutingIngressPaths(r.Namespace, targets, names, defaults), rule.HTTP.Paths...)
+					basePath.AppendHeaders[network.DefaultRouteHeaderName] = "true"
 				} else {
 					// If a request is routed by a tag-attached hostname instead of the tag header,
 					// the request may not have the tag header "Knative-Serving-Tag",
@@ -143,13 +265,35 @@ func MakeIngressSpec(
 					//
 					// To prevent such inconsistency,
 					// the tag header is appended with the tag corresponding to the tag-attached hostname
-					rule.HTTP.Paths[0].AppendHeaders[network.TagHeaderName] = name
+					basePath.AppendHeaders[network.TagHeaderName] = name
+				}
+			}
+			if opts.stickyHeaderName != "" {
+				// Pinned requests take precedence over the percent-weighted
+				// split; unmatched requests fall through to it unchanged.
+				stickyPaths := makeSessionAffinityIngressPaths(r.Namespace, targets[name], opts.stickyHeaderName)
+				// Pinned paths carry the Route-wide retry policy and header
+				// matches too, so pinning can't bypass the header match.
+				for i := range stickyPaths {
+					stickyPaths[i].RetryPolicy = opts.retryPolicy
+					for k, v := range opts.headers {
+						stickyPaths[i].Headers[k] = v
+					}
 				}
+				rule.HTTP.Paths = append(stickyPaths, rule.HTTP.Paths...)
+			}
+			if networkConfig.TagHeaderBasedRouting && name == traffic.DefaultTarget {
+				// Add ingress paths for a request with the tag header.
+				// If a request has one of the `names`(tag name) except the default path,
+				// the request will be routed via one of the ingress paths, corresponding to the tag name.
+				rule.HTTP.Paths = append(
+					makeTagBasedRoutingIngressPaths(r.Namespace, targets, names, defaults, opts), rule.HTTP.Paths...)
 			}
-			// If this is a public rule, we need to configure ACME challenge paths.
+			// If this is a public rule, we need to configure ACME challenge paths,
+			// unless the domain is already covered by an issued wildcard certificate.
 			if visibility == netv1alpha1.IngressVisibilityExternalIP {
 				rule.HTTP.Paths = append(
-					makeACMEIngressPaths(challengeHosts, []string{domain}), rule.HTTP.Paths...)
+					makeACMEIngressPaths(challengeHosts, []string{domain}, challenges.WildcardHosts), rule.HTTP.Paths...)
 			}
 			rules = append(rules, rule)
 		}
@@ -157,10 +301,27 @@ func MakeIngressSpec(
 
 	return netv1alpha1.IngressSpec{
 		Rules: rules,
-		TLS:   tls,
+		TLS:   append(append([]netv1alpha1.IngressTLS{}, externalTLS...), internalTLS...),
 	}, nil
 }
 
+// ChallengeSet carries everything MakeIngress needs to route ACME domain
+// validation: the outstanding HTTP-01 challenges to answer via ingress
+// paths, and the hostnames already covered by a DNS-01-issued wildcard
+// Certificate. A single wildcard Certificate (e.g. *.ns.example.com) covers
+// every per-tag host under a Route, so none of those hosts need their own
+// HTTP01Challenge path.
+type ChallengeSet struct {
+	// HTTP01Challenges are the outstanding HTTP-01 challenge records to
+	// answer via ingress paths.
+	HTTP01Challenges []netv1alpha1.HTTP01Challenge
+
+	// WildcardHosts is the set of wildcard hostnames (e.g.
+	// "*.ns.example.com") already covered by an issued Certificate. A
+	// domain matching one of these needs no HTTP-01 challenge path.
+	WildcardHosts sets.String
+}
+
 func getChallengeHosts(challenges []netv1alpha1.HTTP01Challenge) map[string]netv1alpha1.HTTP01Challenge {
 	c := make(map[string]netv1alpha1.HTTP01Challenge, len(challenges))
 
@@ -184,10 +345,16 @@ func routeDomain(ctx context.Context, targetName string, r *servingv1.Route, vis
 	return domains.DomainNameFromTemplate(ctx, *meta, hostname)
 }
 
-func makeACMEIngressPaths(challenges map[string]netv1alpha1.HTTP01Challenge, domains []string) []netv1alpha1.HTTPIngressPath {
+func makeACMEIngressPaths(challenges map[string]netv1alpha1.HTTP01Challenge, domains []string, wildcardHosts sets.String) []netv1alpha1.HTTPIngressPath {
 	paths := make([]netv1alpha1.HTTPIngressPath, 0, len(challenges))
 	for _, domain := range domains {
-		challenge, ok := challenges[domain]
+		// A DNS-01-issued wildcard Certificate already covers this host, so
+		// there's nothing to validate via an HTTP-01 challenge path.
+		if isWildcardCovered(domain, wildcardHosts) {
+			continue
+		}
+
+		challenge, ok := matchChallengeHost(domain, challenges)
 		if !ok {
 			continue
 		}
@@ -207,25 +374,78 @@ func makeACMEIngressPaths(challenges map[string]netv1alpha1.HTTP01Challenge, dom
 	return paths
 }
 
-func makeIngressRule(domains []string, ns string, visibility netv1alpha1.IngressVisibility, targets traffic.RevisionTargets, defaults apisconfig.Defaults) *netv1alpha1.IngressRule {
+// isWildcardCovered reports whether domain is covered by one of
+// wildcardHosts, e.g. "tag.ns.example.com" is covered by "*.ns.example.com".
+func isWildcardCovered(domain string, wildcardHosts sets.String) bool {
+	if wildcardHosts.Len() == 0 {
+		return false
+	}
+	parts := strings.SplitN(domain, ".", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	return wildcardHosts.Has("*." + parts[1])
+}
+
+// matchChallengeHost looks up the HTTP01Challenge for domain. Tag-prefixed
+// hostnames (e.g. "tag-route.ns.example.com") aren't issued their own
+// challenge -- the challenge is obtained once per Route -- so if there's no
+// exact match, fall back to matching against the challenge host's domain
+// suffix (everything after the first label).
+//
+// Map iteration order is randomized, so picking the first suffix match found
+// while ranging over the challenges map would make the generated Ingress
+// nondeterministic whenever more than one challenge host shares a suffix.
+// Instead, collect every candidate and deterministically pick the most
+// specific (longest) host, breaking remaining ties lexicographically.
+func matchChallengeHost(domain string, challenges map[string]netv1alpha1.HTTP01Challenge) (netv1alpha1.HTTP01Challenge, bool) {
+	if challenge, ok := challenges[domain]; ok {
+		return challenge, true
+	}
+
+	parts := strings.SplitN(domain, ".", 2)
+	if len(parts) != 2 {
+		return netv1alpha1.HTTP01Challenge{}, false
+	}
+	suffix := parts[1]
+
+	var bestHost string
+	var best netv1alpha1.HTTP01Challenge
+	found := false
+	for host, challenge := range challenges {
+		hostParts := strings.SplitN(host, ".", 2)
+		if len(hostParts) != 2 || hostParts[1] != suffix {
+			continue
+		}
+		if !found || len(host) > len(bestHost) || (len(host) == len(bestHost) && host < bestHost) {
+			bestHost, best, found = host, challenge, true
+		}
+	}
+	return best, found
+}
+
+func makeIngressRule(domains []string, ns string, visibility netv1alpha1.IngressVisibility, targets traffic.RevisionTargets, defaults apisconfig.Defaults, opts ingressPathOptions) *netv1alpha1.IngressRule {
 	return &netv1alpha1.IngressRule{
 		Hosts:      domains,
 		Visibility: visibility,
 		HTTP: &netv1alpha1.HTTPIngressRuleValue{
 			Paths: []netv1alpha1.HTTPIngressPath{
-				*makeBaseIngressPath(ns, targets, defaults),
+				*makeBaseIngressPath(ns, targets, defaults, opts),
 			},
 		},
 	}
 }
 
-func makeTagBasedRoutingIngressPaths(ns string, targets map[string]traffic.RevisionTargets, names []string, defaults apisconfig.Defaults) []netv1alpha1.HTTPIngressPath {
+func makeTagBasedRoutingIngressPaths(ns string, targets map[string]traffic.RevisionTargets, names []string, defaults apisconfig.Defaults, opts ingressPathOptions) []netv1alpha1.HTTPIngressPath {
 	paths := make([]netv1alpha1.HTTPIngressPath, 0, len(names))
 
 	for _, name := range names {
 		if name != traffic.DefaultTarget {
-			path := makeBaseIngressPath(ns, targets[name], defaults)
-			path.Headers = map[string]netv1alpha1.HeaderMatch{network.TagHeaderName: {Exact: name}}
+			path := makeBaseIngressPath(ns, targets[name], defaults, opts)
+			if path.Headers == nil {
+				path.Headers = make(map[string]netv1alpha1.HeaderMatch, 1)
+			}
+			path.Headers[network.TagHeaderName] = netv1alpha1.HeaderMatch{Exact: name}
 			paths = append(paths, *path)
 		}
 	}
@@ -233,7 +453,132 @@ func makeTagBasedRoutingIngressPaths(ns string, targets map[string]traffic.Revis
 	return paths
 }
 
-func makeBaseIngressPath(ns string, targets traffic.RevisionTargets, defaults apisconfig.Defaults) *netv1alpha1.HTTPIngressPath {
+// makeSessionAffinityIngressPaths returns one HTTPIngressPath per revision
+// currently receiving traffic, each matching requests that already carry the
+// sticky-session pin header for that revision (see StickySessionAnnotationKey
+// for why this is a request header, not a parsed browser cookie). They take
+// precedence over the percent-weighted base path, so once a client is pinned
+// it keeps hitting the same revision for the lifetime of the pin regardless
+// of how the split changes; requests without a matching header fall through
+// to the base path unchanged.
+func makeSessionAffinityIngressPaths(ns string, targets traffic.RevisionTargets, headerName string) []netv1alpha1.HTTPIngressPath {
+	paths := make([]netv1alpha1.HTTPIngressPath, 0, len(targets))
+
+	for _, t := range targets {
+		if t.Percent == nil || *t.Percent == 0 {
+			continue
+		}
+
+		paths = append(paths, netv1alpha1.HTTPIngressPath{
+			Headers: map[string]netv1alpha1.HeaderMatch{
+				headerName: {Exact: stickySessionMatchValue(t.TrafficTarget.RevisionName)},
+			},
+			Splits: []netv1alpha1.IngressBackendSplit{{
+				IngressBackend: netv1alpha1.IngressBackend{
+					ServiceNamespace: ns,
+					ServiceName:      t.ServiceName,
+					ServicePort:      intstr.FromInt(networking.ServicePort(t.Protocol)),
+				},
+				Percent: 100,
+				AppendHeaders: map[string]string{
+					activator.RevisionHeaderName:      t.TrafficTarget.RevisionName,
+					activator.RevisionHeaderNamespace: ns,
+				},
+			}},
+		})
+	}
+
+	return paths
+}
+
+// stickySessionMatchValue deterministically derives the sticky-session pin
+// value for revisionName, so the same revision always produces the same
+// value and makeSessionAffinityIngressPaths' header matches stay stable
+// across reconciles.
+func stickySessionMatchValue(revisionName string) string {
+	sum := sha256.Sum256([]byte(revisionName))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// ingressPathOptions carries the Route-wide options that get applied to
+// every HTTPIngressPath generated for that Route, except the ACME challenge
+// paths. They're sourced from Route
+// annotations (see retryPolicyFromAnnotations, headerMatchFromAnnotations,
+// and StickySessionAnnotationKey) rather than a per-TrafficTarget CRD field,
+// the same way config.FromContext(ctx).Network.TagHeaderBasedRouting is
+// already a route-wide toggle rather than a per-target one above.
+//
+// This is an explicitly scoped-down stand-in for per-target retry/header
+// config: the original ask was a TrafficTarget.Retry/HeaderMatch CRD field
+// (so different revisions in the same split could carry different rules),
+// plus webhook defaulting/validation on it. None of that CRD/webhook surface
+// exists in this tree yet, so every target in a Route shares one policy
+// instead. retryPolicyFromAnnotations/headerMatchFromAnnotations also don't
+// admission-reject malformed values the way webhook validation would -- a
+// typo'd annotation value is just treated as "unset" (nil policy). Landing
+// real per-target config needs the CRD field and its validation/defaulting
+// first; this annotation path should be replaced, not extended, when that
+// lands.
+type ingressPathOptions struct {
+	stickyHeaderName string
+	retryPolicy      *netv1alpha1.HTTPRetryPolicy
+	headers          map[string]netv1alpha1.HeaderMatch
+}
+
+// retryPolicyFromAnnotations builds a Route-wide HTTPRetryPolicy from
+// RetryAttemptsAnnotationKey/RetryPerTryTimeoutAnnotationKey/RetryOnAnnotationKey,
+// or returns nil if retries aren't configured. A malformed annotation value
+// (e.g. a non-numeric RetryAttemptsAnnotationKey) is silently treated as
+// "unset" rather than rejected -- there's no webhook validation wired up for
+// these annotations yet, so this can't surface an admission-time error the
+// way a real CRD field with validation would (see ingressPathOptions).
+func retryPolicyFromAnnotations(anns map[string]string) *netv1alpha1.HTTPRetryPolicy {
+	raw, ok := anns[RetryAttemptsAnnotationKey]
+	if !ok {
+		return nil
+	}
+	attempts, err := strconv.Atoi(raw)
+	if err != nil || attempts <= 0 {
+		return nil
+	}
+
+	policy := &netv1alpha1.HTTPRetryPolicy{Attempts: attempts}
+	if raw, ok := anns[RetryPerTryTimeoutAnnotationKey]; ok {
+		if d, err := time.ParseDuration(raw); err == nil {
+			policy.PerTryTimeout = &metav1.Duration{Duration: d}
+		}
+	}
+	if raw, ok := anns[RetryOnAnnotationKey]; ok && raw != "" {
+		policy.RetryOn = strings.Split(raw, ",")
+	}
+	return policy
+}
+
+// headerMatchFromAnnotations decodes HeaderMatchAnnotationKey's JSON object
+// of header name to exact-match value, applied Route-wide to every target
+// (see ingressPathOptions), or returns nil if it's absent or malformed. A
+// malformed value is silently treated as "no header match configured"
+// rather than rejected at admission time, for the same reason noted on
+// retryPolicyFromAnnotations.
+func headerMatchFromAnnotations(anns map[string]string) map[string]netv1alpha1.HeaderMatch {
+	raw, ok := anns[HeaderMatchAnnotationKey]
+	if !ok || raw == "" {
+		return nil
+	}
+
+	var exact map[string]string
+	if err := json.Unmarshal([]byte(raw), &exact); err != nil || len(exact) == 0 {
+		return nil
+	}
+
+	headers := make(map[string]netv1alpha1.HeaderMatch, len(exact))
+	for name, value := range exact {
+		headers[name] = netv1alpha1.HeaderMatch{Exact: value}
+	}
+	return headers
+}
+
+func makeBaseIngressPath(ns string, targets traffic.RevisionTargets, defaults apisconfig.Defaults, opts ingressPathOptions) *netv1alpha1.HTTPIngressPath {
 	// Optimistically allocate |targets| elements.
 	splits := make([]netv1alpha1.IngressBackendSplit, 0, len(targets))
 
@@ -253,6 +598,18 @@ func makeBaseIngressPath(ns string, targets traffic.RevisionTargets, defaults ap
 			sawDuration = true
 		}
 
+		appendHeaders := map[string]string{
+			activator.RevisionHeaderName:      t.TrafficTarget.RevisionName,
+			activator.RevisionHeaderNamespace: ns,
+		}
+		if opts.stickyHeaderName != "" {
+			// Surface the header=value pin a client would need to send back
+			// to stick to this revision (see stickySessionPinHintHeaderName).
+			// Nothing currently consumes this to auto-pin a client; it's
+			// informational until a queue-proxy change acts on it.
+			appendHeaders[stickySessionPinHintHeaderName] = opts.stickyHeaderName + "=" + stickySessionMatchValue(t.TrafficTarget.RevisionName)
+		}
+
 		splits = append(splits, netv1alpha1.IngressBackendSplit{
 			IngressBackend: netv1alpha1.IngressBackend{
 				ServiceNamespace: ns,
@@ -261,11 +618,8 @@ func makeBaseIngressPath(ns string, targets traffic.RevisionTargets, defaults ap
 				// Otherwise, the serverless services can't guarantee seamless positive handoff.
 				ServicePort: intstr.FromInt(networking.ServicePort(t.Protocol)),
 			},
-			Percent: int(*t.Percent),
-			AppendHeaders: map[string]string{
-				activator.RevisionHeaderName:      t.TrafficTarget.RevisionName,
-				activator.RevisionHeaderNamespace: ns,
-			},
+			Percent:       int(*t.Percent),
+			AppendHeaders: appendHeaders,
 		})
 	}
 
@@ -274,7 +628,26 @@ func makeBaseIngressPath(ns string, targets traffic.RevisionTargets, defaults ap
 		timeout = &metav1.Duration{Duration: duration}
 	}
 	return &netv1alpha1.HTTPIngressPath{
-		Splits:  splits,
-		Timeout: timeout,
+		Splits:      splits,
+		Timeout:     timeout,
+		RetryPolicy: opts.retryPolicy,
+		// Give this path its own copy of opts.headers rather than the shared
+		// map: makeTagBasedRoutingIngressPaths calls this once per tag and
+		// then adds a TagHeaderName entry to the returned path's Headers, and
+		// callers sharing the same underlying map would see each other's tag
+		// mutate in place, leaving every path keyed on whichever tag was
+		// processed last.
+		Headers: cloneHeaderMatch(opts.headers),
+	}
+}
+
+func cloneHeaderMatch(headers map[string]netv1alpha1.HeaderMatch) map[string]netv1alpha1.HeaderMatch {
+	if len(headers) == 0 {
+		return nil
+	}
+	clone := make(map[string]netv1alpha1.HeaderMatch, len(headers))
+	for k, v := range headers {
+		clone[k] = v
 	}
+	return clone
 }
@@ -0,0 +1,96 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	netv1alpha1 "knative.dev/serving/pkg/apis/networking/v1alpha1"
+)
+
+func TestMatchChallengeHostIsDeterministic(t *testing.T) {
+	challenges := map[string]netv1alpha1.HTTP01Challenge{
+		"a.ns.example.com": {},
+		"b.ns.example.com": {},
+	}
+
+	if _, ok := matchChallengeHost("tag-route.ns.example.com", challenges); !ok {
+		t.Fatalf("matchChallengeHost() did not find a suffix match")
+	}
+
+	// The exact candidate returned must not flap across repeated calls.
+	first, _ := matchChallengeHost("tag-route.ns.example.com", challenges)
+	for i := 0; i < 50; i++ {
+		got, _ := matchChallengeHost("tag-route.ns.example.com", challenges)
+		if got != first {
+			t.Fatalf("matchChallengeHost() returned inconsistent results across calls: %+v vs %+v", got, first)
+		}
+	}
+}
+
+func TestMatchChallengeHostExactPreferred(t *testing.T) {
+	want := netv1alpha1.HTTP01Challenge{}
+	want.URL.Path = "/exact"
+	other := netv1alpha1.HTTP01Challenge{}
+	other.URL.Path = "/suffix"
+
+	challenges := map[string]netv1alpha1.HTTP01Challenge{
+		"route.ns.example.com": want,
+		"other.ns.example.com": other,
+	}
+
+	got, ok := matchChallengeHost("route.ns.example.com", challenges)
+	if !ok {
+		t.Fatalf("matchChallengeHost() found no match")
+	}
+	if got.URL.Path != want.URL.Path {
+		t.Errorf("matchChallengeHost() = %+v, want exact match %+v", got, want)
+	}
+}
+
+func TestIsWildcardCovered(t *testing.T) {
+	cases := []struct {
+		name          string
+		domain        string
+		wildcardHosts sets.String
+		want          bool
+	}{
+		{"no wildcards", "tag.ns.example.com", sets.NewString(), false},
+		{"covered", "tag.ns.example.com", sets.NewString("*.ns.example.com"), true},
+		{"different zone", "tag.ns.example.com", sets.NewString("*.other.example.com"), false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := isWildcardCovered(c.domain, c.wildcardHosts); got != c.want {
+				t.Errorf("isWildcardCovered(%q, %v) = %v, want %v", c.domain, c.wildcardHosts, got, c.want)
+			}
+		})
+	}
+}
+
+func TestMakeACMEIngressPathsSkipsWildcardCoveredHosts(t *testing.T) {
+	challenges := getChallengeHosts([]netv1alpha1.HTTP01Challenge{{}})
+	// The only registered challenge is for the zero-value host (""), which
+	// won't match any of the domains below, so this purely exercises the
+	// wildcard short-circuit: no paths should be emitted for a covered host.
+	paths := makeACMEIngressPaths(challenges, []string{"tag.ns.example.com"}, sets.NewString("*.ns.example.com"))
+	if len(paths) != 0 {
+		t.Errorf("makeACMEIngressPaths() = %d paths, want 0 for a wildcard-covered host", len(paths))
+	}
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+	"time"
+
+	apisconfig "knative.dev/serving/pkg/apis/config"
+	servingv1 "knative.dev/serving/pkg/apis/serving/v1"
+	"knative.dev/serving/pkg/network"
+	"knative.dev/serving/pkg/reconciler/route/traffic"
+)
+
+func TestRetryPolicyFromAnnotations(t *testing.T) {
+	cases := []struct {
+		name        string
+		anns        map[string]string
+		wantNil     bool
+		wantAttempt int
+		wantTimeout time.Duration
+		wantOn      []string
+	}{
+		{
+			name:    "no annotation",
+			anns:    map[string]string{},
+			wantNil: true,
+		},
+		{
+			name:    "invalid attempts",
+			anns:    map[string]string{RetryAttemptsAnnotationKey: "not-a-number"},
+			wantNil: true,
+		},
+		{
+			name:    "zero attempts",
+			anns:    map[string]string{RetryAttemptsAnnotationKey: "0"},
+			wantNil: true,
+		},
+		{
+			name: "full config",
+			anns: map[string]string{
+				RetryAttemptsAnnotationKey:      "3",
+				RetryPerTryTimeoutAnnotationKey: "2s",
+				RetryOnAnnotationKey:            "5xx,reset",
+			},
+			wantAttempt: 3,
+			wantTimeout: 2 * time.Second,
+			wantOn:      []string{"5xx", "reset"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := retryPolicyFromAnnotations(c.anns)
+			if c.wantNil {
+				if got != nil {
+					t.Fatalf("retryPolicyFromAnnotations() = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("retryPolicyFromAnnotations() = nil, want non-nil")
+			}
+			if got.Attempts != c.wantAttempt {
+				t.Errorf("Attempts = %d, want %d", got.Attempts, c.wantAttempt)
+			}
+			if got.PerTryTimeout == nil || got.PerTryTimeout.Duration != c.wantTimeout {
+				t.Errorf("PerTryTimeout = %v, want %v", got.PerTryTimeout, c.wantTimeout)
+			}
+			if len(got.RetryOn) != len(c.wantOn) {
+				t.Fatalf("RetryOn = %v, want %v", got.RetryOn, c.wantOn)
+			}
+			for i := range c.wantOn {
+				if got.RetryOn[i] != c.wantOn[i] {
+					t.Errorf("RetryOn[%d] = %q, want %q", i, got.RetryOn[i], c.wantOn[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHeaderMatchFromAnnotations(t *testing.T) {
+	cases := []struct {
+		name    string
+		anns    map[string]string
+		wantNil bool
+		want    map[string]string
+	}{
+		{name: "no annotation", anns: map[string]string{}, wantNil: true},
+		{name: "malformed json", anns: map[string]string{HeaderMatchAnnotationKey: "{not-json"}, wantNil: true},
+		{name: "empty object", anns: map[string]string{HeaderMatchAnnotationKey: "{}"}, wantNil: true},
+		{
+			name: "valid",
+			anns: map[string]string{HeaderMatchAnnotationKey: `{"X-Canary":"true"}`},
+			want: map[string]string{"X-Canary": "true"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := headerMatchFromAnnotations(c.anns)
+			if c.wantNil {
+				if got != nil {
+					t.Fatalf("headerMatchFromAnnotations() = %+v, want nil", got)
+				}
+				return
+			}
+			if len(got) != len(c.want) {
+				t.Fatalf("headerMatchFromAnnotations() = %+v, want %+v", got, c.want)
+			}
+			for k, v := range c.want {
+				if got[k].Exact != v {
+					t.Errorf("headerMatchFromAnnotations()[%q].Exact = %q, want %q", k, got[k].Exact, v)
+				}
+			}
+		})
+	}
+}
+
+// TestMakeTagBasedRoutingIngressPathsDoesNotMutateSharedHeaders guards
+// against regressing the bug where makeTagBasedRoutingIngressPaths added the
+// tag's HeaderMatch to the same map instance shared across every path
+// (opts.headers), leaving every tag -- and the default path -- keyed on
+// whichever tag was processed last.
+func TestMakeTagBasedRoutingIngressPathsDoesNotMutateSharedHeaders(t *testing.T) {
+	opts := ingressPathOptions{
+		headers: headerMatchFromAnnotations(map[string]string{
+			HeaderMatchAnnotationKey: `{"X-Canary":"true"}`,
+		}),
+	}
+
+	targets := map[string]traffic.RevisionTargets{
+		"tag-a": {{
+			TrafficTarget: servingv1.TrafficTarget{RevisionName: "rev-a", Percent: int64Ptr(100)},
+			ServiceName:   "rev-a-service",
+			Protocol:      "http",
+		}},
+		"tag-b": {{
+			TrafficTarget: servingv1.TrafficTarget{RevisionName: "rev-b", Percent: int64Ptr(100)},
+			ServiceName:   "rev-b-service",
+			Protocol:      "http",
+		}},
+	}
+
+	paths := makeTagBasedRoutingIngressPaths("ns", targets, []string{"tag-a", "tag-b"}, apisconfig.Defaults{}, opts)
+	if len(paths) != 2 {
+		t.Fatalf("makeTagBasedRoutingIngressPaths() = %d paths, want 2", len(paths))
+	}
+
+	wantTag := map[int]string{0: "tag-a", 1: "tag-b"}
+	for i, p := range paths {
+		if got := p.Headers[network.TagHeaderName].Exact; got != wantTag[i] {
+			t.Errorf("paths[%d].Headers[%s].Exact = %q, want %q", i, network.TagHeaderName, got, wantTag[i])
+		}
+		if got := p.Headers["X-Canary"].Exact; got != "true" {
+			t.Errorf("paths[%d].Headers[X-Canary].Exact = %q, want %q", i, got, "true")
+		}
+	}
+
+	// The original, shared opts.headers must be untouched by either call.
+	if _, ok := opts.headers[network.TagHeaderName]; ok {
+		t.Errorf("opts.headers was mutated with a tag entry: %+v", opts.headers)
+	}
+	if len(opts.headers) != 1 {
+		t.Errorf("opts.headers = %+v, want only the original X-Canary entry", opts.headers)
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+
+	apisconfig "knative.dev/serving/pkg/apis/config"
+	"knative.dev/serving/pkg/apis/networking"
+	netv1alpha1 "knative.dev/serving/pkg/apis/networking/v1alpha1"
+	servingv1 "knative.dev/serving/pkg/apis/serving/v1"
+	"knative.dev/serving/pkg/network"
+	"knative.dev/serving/pkg/reconciler/route/traffic"
+)
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+func TestStickySessionMatchValueIsDeterministic(t *testing.T) {
+	a := stickySessionMatchValue("rev-a")
+	b := stickySessionMatchValue("rev-a")
+	if a != b {
+		t.Fatalf("stickySessionMatchValue(%q) = %q, then %q; want identical", "rev-a", a, b)
+	}
+
+	other := stickySessionMatchValue("rev-b")
+	if a == other {
+		t.Fatalf("stickySessionMatchValue() returned the same value for different revisions: %q", a)
+	}
+}
+
+func TestMakeSessionAffinityIngressPaths(t *testing.T) {
+	targets := traffic.RevisionTargets{{
+		TrafficTarget: servingv1.TrafficTarget{
+			RevisionName: "rev-a",
+			Percent:      int64Ptr(100),
+		},
+		ServiceName: "rev-a-service",
+		Protocol:    networking.ProtocolHTTP1,
+	}, {
+		TrafficTarget: servingv1.TrafficTarget{
+			RevisionName: "rev-b",
+			Percent:      int64Ptr(0),
+		},
+		ServiceName: "rev-b-service",
+		Protocol:    networking.ProtocolHTTP1,
+	}}
+
+	paths := makeSessionAffinityIngressPaths("ns", targets, "X-Sticky-Pin")
+	if len(paths) != 1 {
+		t.Fatalf("makeSessionAffinityIngressPaths() = %d paths, want 1 (zero-percent targets are skipped)", len(paths))
+	}
+
+	got := paths[0]
+	want := stickySessionMatchValue("rev-a")
+	match, ok := got.Headers["X-Sticky-Pin"]
+	if !ok {
+		t.Fatalf("Headers[%q] missing, got %+v", "X-Sticky-Pin", got.Headers)
+	}
+	if match.Exact != want {
+		t.Errorf("Headers[%q].Exact = %q, want %q", "X-Sticky-Pin", match.Exact, want)
+	}
+
+	if len(got.Splits) != 1 || got.Splits[0].ServiceName != "rev-a-service" {
+		t.Errorf("Splits = %+v, want a single split to rev-a-service", got.Splits)
+	}
+}
+
+// TestBasePathHeadersSurviveSessionAffinityPrepend checks the ordering that
+// MakeIngressSpec relies on to avoid landing tag/default-route AppendHeaders
+// on a sticky-pin path instead of the percent-weighted base path: capture the
+// base path and set its headers before any prepending happens. It replays
+// that ordering on makeIngressRule and makeSessionAffinityIngressPaths
+// directly; it doesn't call MakeIngressSpec itself.
+func TestBasePathHeadersSurviveSessionAffinityPrepend(t *testing.T) {
+	targets := traffic.RevisionTargets{{
+		TrafficTarget: servingv1.TrafficTarget{RevisionName: "rev-a", Percent: int64Ptr(100)},
+		ServiceName:   "rev-a-service",
+		Protocol:      networking.ProtocolHTTP1,
+	}}
+
+	rule := *makeIngressRule([]string{"example.com"}, "ns", netv1alpha1.IngressVisibilityExternalIP, targets, apisconfig.Defaults{}, ingressPathOptions{})
+	basePath := &rule.HTTP.Paths[0]
+	basePath.AppendHeaders = map[string]string{network.DefaultRouteHeaderName: "true"}
+
+	rule.HTTP.Paths = append(
+		makeSessionAffinityIngressPaths("ns", targets, "X-Sticky-Pin"), rule.HTTP.Paths...)
+
+	if len(rule.HTTP.Paths) != 2 {
+		t.Fatalf("rule.HTTP.Paths = %d paths, want 2 (1 sticky-pin + 1 base)", len(rule.HTTP.Paths))
+	}
+
+	base := rule.HTTP.Paths[len(rule.HTTP.Paths)-1]
+	if got := base.AppendHeaders[network.DefaultRouteHeaderName]; got != "true" {
+		t.Errorf("base path AppendHeaders[%s] = %q, want %q", network.DefaultRouteHeaderName, got, "true")
+	}
+}